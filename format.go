@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ThisGuyCodes/cisco/nat"
+)
+
+var tableHeader = []string{
+	"proto", "inside_local", "inside_local_port", "outside_global", "outside_global_port",
+	"created", "used", "timeout",
+}
+
+func natRow(n *nat.NAT) []string {
+	return []string{
+		nat.NAT_NAMES[n.Proto],
+		n.InsideLocal.String(), fmt.Sprint(n.InsideLocalPort),
+		n.OutsideGlobal.String(), fmt.Sprint(n.OutsideGlobalPort),
+		n.Created.Format(nat.DATE_FORMAT), n.Used.Format(nat.DATE_FORMAT),
+		n.Timeout.String(),
+	}
+}
+
+func writeTable(w io.Writer, nats nat.NATS) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, joinTab(tableHeader))
+	for _, n := range nats {
+		fmt.Fprintln(tw, joinTab(natRow(n)))
+	}
+	return tw.Flush()
+}
+
+func joinTab(fields []string) string {
+	out := fields[0]
+	for _, f := range fields[1:] {
+		out += "\t" + f
+	}
+	return out
+}
+
+func writeCSV(w io.Writer, nats nat.NATS) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(tableHeader); err != nil {
+		return err
+	}
+	for _, n := range nats {
+		if err := cw.Write(natRow(n)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, nats nat.NATS) error {
+	return json.NewEncoder(w).Encode(nats)
+}
@@ -1,247 +1,190 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net"
+	"net/http"
 	"os"
-	"regexp"
-	"strconv"
+	"os/signal"
 	"time"
-)
-
-type NAT struct {
-	Proto             NATProto
-	InsideGlobal      net.IP
-	InsideGlobalPort  int
-	InsideLocal       net.IP
-	InsideLocalPort   int
-	OutsideLocal      net.IP
-	OutsideLocalPort  int
-	OutsideGlobal     net.IP
-	OutsideGlobalPort int
-	Created           time.Time
-	Used              time.Time
-	Timeout           time.Duration
-}
-
-type NATS []*NAT
-
-func (nats NATS) Where(fn func(*NAT) bool) NATS {
-	filtered := make(NATS, 0)
-	for _, nat := range nats {
-		if fn(nat) {
-			filtered = append(filtered, nat)
-		}
-	}
-	return filtered
-}
-
-type NATProto uint8
-
-func (nat NATProto) MarshalJSON() ([]byte, error) {
-	return json.Marshal(NAT_NAMES[nat])
-}
-
-func (nat *NATProto) UnmarshalJSON(data []byte) error {
-	var dest string
-	err := json.Unmarshal(data, &dest)
-	*nat = NAT_REVERSE_NAMES[dest]
-	return err
-}
 
-const (
-	UDP_NAT NATProto = iota
-	TCP_NAT
-	STATIC_NAT
-	ICMP_NAT
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	DATE_FORMAT = "01/02/06 15:04:05"
+	"github.com/ThisGuyCodes/cisco/nat"
 )
 
-var (
-	ROUTE_SEP    = []byte("\n\n")
-	ROUTE_HEADER = []byte("Pro")
+func main() {
+	var (
+		sourceFlag    = flag.String("source", "text", "NAT source to read from: text, ssh, snmp")
+		sshAddr       = flag.String("ssh-addr", "", "host:port of the router to connect to (for -source=ssh)")
+		sshUser       = flag.String("ssh-user", "", "SSH username (for -source=ssh)")
+		sshPass       = flag.String("ssh-pass", "", "SSH password (for -source=ssh)")
+		sshKnownHosts = flag.String("ssh-known-hosts", "", "path to a known_hosts file to verify the router's host key against (for -source=ssh)")
+		snmpAddr      = flag.String("snmp-addr", "", "host:port of the router to connect to (for -source=snmp)")
+		snmpCommunity = flag.String("snmp-community", "public", "SNMP community string (for -source=snmp)")
+
+		watch  = flag.Duration("watch", 0, "if set, poll the source on this interval and emit lifecycle events instead of a one-shot report")
+		maxAge = flag.Duration("max-age", 10*time.Minute, "how long a removed translation is remembered, to avoid re-reporting it (only with -watch)")
+		sink   = flag.String("sink", "-", "where -watch events are written: '-' for stdout, a file path, unix:/path/to.sock, or http(s)://host/path")
 
-	ROUTE_REGEXP    = regexp.MustCompile(`^(-{3}|tcp|udp|icmp)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)$`)
-	TIME_REGEXP     = regexp.MustCompile(`^\s+create:\s+([^,]+),\s+use:\s+([^,]+),\s+timeout:\s+([^,]+)$`)
-	DURATION_REGEXP = regexp.MustCompile(`^(\d\d):(\d\d):(\d\d)$`)
+		listen        = flag.String("listen", "", "if set, serve Prometheus metrics on this address (e.g. :9107) instead of a one-shot report")
+		longThreshold = flag.Duration("long-threshold", time.Hour, "timeout above which a translation counts towards cisco_nat_translations_long")
 
-	DURATION_REPLACE = []byte(`${1}h${2}m${3}s`)
+		filterExpr = flag.String("filter", "", "if set, select only NAT records matching this expression (e.g. 'proto=tcp && timeout>1h') and print them instead of the one-shot report")
+		format     = flag.String("format", "table", "output format for -filter results: table, json, csv")
+	)
+	flag.Parse()
 
-	NAT_TRANSLATION = map[byte]NATProto{
-		byte('u'): UDP_NAT,
-		byte('t'): TCP_NAT,
-		byte('-'): STATIC_NAT,
-		byte('i'): ICMP_NAT,
+	newSrc := func() (nat.Source, error) {
+		return newSource(*sourceFlag, *sshAddr, *sshUser, *sshPass, *sshKnownHosts, *snmpAddr, *snmpCommunity)
 	}
 
-	NAT_NAMES = map[NATProto]string{
-		UDP_NAT:    "udp",
-		TCP_NAT:    "tcp",
-		STATIC_NAT: "static",
-		ICMP_NAT:   "icmp",
+	if *listen != "" {
+		log.Fatalln(runExporter(*listen, *longThreshold, newSrc))
 	}
-	NAT_REVERSE_NAMES = reverseNATNames(NAT_NAMES)
-)
 
-func reverseNATNames(names map[NATProto]string) map[string]NATProto {
-	reversed := make(map[string]NATProto, len(names))
-	for key, value := range names {
-		reversed[value] = key
+	if *watch > 0 {
+		if err := runWatch(*watch, *maxAge, *sink, newSrc); err != nil {
+			log.Fatalln(err)
+		}
+		return
 	}
-	return reversed
-}
 
-func routeSplitFunc(data []byte, atEOF bool) (int, []byte, error) {
-	if len(data) == 0 && atEOF {
-		return 0, nil, nil
+	src, err := newSrc()
+	if err != nil {
+		log.Fatalln(err)
 	}
-	from, to, advance := 0, 0, 0
-
-	i := bytes.Index(data, ROUTE_SEP)
-	if i == -1 {
-		if !atEOF {
-			// We don't have a whole route, request more data
-			return 0, nil, nil
-		} else {
-			if bytes.HasSuffix(data, []byte("\n")) {
-				// Ends in a newline at EOF, we're done
-				return len(data), data[:len(data)-1], nil
-			} else {
-				return 0, nil, errors.New("Improperly formatted file: it must end with an empty line")
-			}
-		}
-	} else {
-		to = i
-		// We want to omit the seperator
-		advance = i + len(ROUTE_SEP)
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
 	}
 
-	if bytes.HasPrefix(data, ROUTE_HEADER) {
-		// This includes the header, we need to omit it
-		// Find the end of the header line, and omit the newline character
-		from = bytes.Index(data, []byte("\n")) + 1
+	if *filterExpr != "" {
+		if err := runQuery(src, *filterExpr, *format); err != nil {
+			log.Fatalln(err)
+		}
+		return
 	}
 
-	return advance, data[from:to], nil
+	runReport(src)
 }
 
-func (nat *NAT) Parse(data []byte) error {
-	var err error
-	lines := bytes.SplitN(data, []byte("\n"), 3)
-	ips := ROUTE_REGEXP.FindSubmatch(lines[0])
-
-	nat.Proto = NAT_TRANSLATION[ips[1][0]]
-
-	if nat.Proto == STATIC_NAT {
-		nat.InsideGlobal = net.ParseIP(string(ips[2]))
-		nat.InsideLocal = net.ParseIP(string(ips[3]))
-		nat.OutsideLocal = net.ParseIP(string(ips[4]))
-		nat.OutsideGlobal = net.ParseIP(string(ips[5]))
-	} else {
-		nat.InsideGlobal, nat.InsideGlobalPort, err = parseIpPort(ips[2], "Inside Global")
-		if err != nil {
-			return err
-		}
-
-		nat.InsideLocal, nat.InsideLocalPort, err = parseIpPort(ips[3], "Inside Local")
-		if err != nil {
-			return err
-		}
+// runQuery collects src's records, applies filterExpr, and writes the
+// matches to stdout in format.
+func runQuery(src nat.Source, filterExpr, format string) error {
+	pred, err := nat.CompileFilter(filterExpr)
+	if err != nil {
+		return fmt.Errorf("compiling filter: %w", err)
+	}
 
-		nat.OutsideLocal, nat.OutsideLocalPort, err = parseIpPort(ips[4], "Outside Local")
-		if err != nil {
-			return err
+	var all nat.NATS
+	for {
+		n, err := src.Next()
+		if err == io.EOF {
+			break
 		}
-
-		nat.OutsideGlobal, nat.OutsideGlobalPort, err = parseIpPort(ips[5], "Outside Global")
 		if err != nil {
 			return err
 		}
+		all = append(all, n)
 	}
+	matched := all.Where(pred)
 
-	times := TIME_REGEXP.FindSubmatch(lines[1])
-
-	nat.Created, err = time.Parse(DATE_FORMAT, string(times[1]))
-	if err != nil {
-		return err
+	switch format {
+	case "table":
+		return writeTable(os.Stdout, matched)
+	case "json":
+		return writeJSON(os.Stdout, matched)
+	case "csv":
+		return writeCSV(os.Stdout, matched)
+	default:
+		return fmt.Errorf("unknown format %q", format)
 	}
+}
 
-	nat.Used, err = time.Parse(DATE_FORMAT, string(times[2]))
+// runWatch polls newSrc on the given interval, writing lifecycle events to
+// sink until interrupted.
+func runWatch(interval, maxAge time.Duration, sinkSpec string, newSrc func() (nat.Source, error)) error {
+	s, err := openSink(sinkSpec)
 	if err != nil {
-		return err
+		return fmt.Errorf("opening sink: %w", err)
 	}
+	defer s.Close()
 
-	timeout := DURATION_REGEXP.ReplaceAll(times[3], DURATION_REPLACE)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	nat.Timeout, err = time.ParseDuration(string(timeout))
-	return err
-}
+	watcher := &nat.Watcher{
+		NewSource: newSrc,
+		Interval:  interval,
+		MaxAge:    maxAge,
+	}
 
-func parseIpPort(data []byte, name string) (net.IP, int, error) {
-	host, port, err := net.SplitHostPort(string(data))
-	if err != nil {
-		errStr := fmt.Sprintf("Could not parse %s address: %s", name, err)
-		return nil, 0, errors.New(errStr)
+	err = watcher.Run(ctx, s.Write)
+	if err == context.Canceled {
+		return nil
 	}
+	return err
+}
 
-	hostIp := net.ParseIP(host)
-	hostPort, err := strconv.Atoi(port)
+// runExporter serves Prometheus metrics derived from newSrc on addr until
+// the server fails.
+func runExporter(addr string, longThreshold time.Duration, newSrc func() (nat.Source, error)) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&natCollector{newSource: newSrc, longThreshold: longThreshold})
 
-	if err != nil {
-		errStr := fmt.Sprintf("Could not parse %s port: %s", name, err)
-		return hostIp, 0, errors.New(errStr)
-	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 
-	return hostIp, hostPort, nil
+	log.Printf("serving metrics on %s/metrics", addr)
+	return http.ListenAndServe(addr, mux)
 }
 
-func main() {
-	routeScanner := bufio.NewScanner(os.Stdin)
-	routeScanner.Split(routeSplitFunc)
-
+// runReport prints the one-shot summary the tool originally produced:
+// per-protocol counts and the share of translations with a long time left.
+func runReport(src nat.Source) {
 	fmt.Println("Getting data")
-	var nats NATS
-	for routeScanner.Scan() {
-		nat := new(NAT)
-		err := nat.Parse(routeScanner.Bytes())
+	var nats nat.NATS
+	for {
+		n, err := src.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
 			log.Fatalln(err)
 		}
-		nats = append(nats, nat)
+		nats = append(nats, n)
 	}
 	fmt.Println("Data parsed, getting counts")
 
 	long_time := 1 * time.Hour
-	long_time_left := func(nat *NAT) bool { return nat.Timeout > long_time }
+	long_time_left := func(n *nat.NAT) bool { return n.Timeout > long_time }
 
-	nat_type := func(t NATProto) func(nat *NAT) bool {
-		return func(nat *NAT) bool {
-			return nat.Proto == t
+	nat_type := func(t nat.NATProto) func(*nat.NAT) bool {
+		return func(n *nat.NAT) bool {
+			return n.Proto == t
 		}
 	}
 
-	udp_nats := nats.Where(nat_type(UDP_NAT))
-	tcp_nats := nats.Where(nat_type(TCP_NAT))
-	icmp_nats := nats.Where(nat_type(ICMP_NAT))
+	udp_nats := nats.Where(nat_type(nat.UDP_NAT))
+	tcp_nats := nats.Where(nat_type(nat.TCP_NAT))
+	icmp_nats := nats.Where(nat_type(nat.ICMP_NAT))
 
 	long_udp_nats := udp_nats.Where(long_time_left)
 	long_tcp_nats := tcp_nats.Where(long_time_left)
 	long_icmp_nats := icmp_nats.Where(long_time_left)
 
 	var sum_udp_timeout time.Duration
-	for _, nat := range udp_nats {
-		sum_udp_timeout += nat.Timeout
+	for _, n := range udp_nats {
+		sum_udp_timeout += n.Timeout
 	}
 
 	var sum_tcp_timeout time.Duration
-	for _, nat := range tcp_nats {
-		sum_tcp_timeout += nat.Timeout
+	for _, n := range tcp_nats {
+		sum_tcp_timeout += n.Timeout
 	}
 
 	fmt.Printf("average udp timeout: %v\n", sum_udp_timeout/time.Duration(len(udp_nats)))
@@ -256,5 +199,24 @@ icmp: %[8]d | >%[1]v left: %[9]d (%[10]d%%)
 		len(udp_nats), len(long_udp_nats), len(long_udp_nats)*100/len(udp_nats),
 		len(tcp_nats), len(long_tcp_nats), len(long_tcp_nats)*100/len(tcp_nats),
 		len(icmp_nats), len(long_icmp_nats), len(long_icmp_nats)*100/len(icmp_nats))
+}
 
+// newSource builds the nat.Source selected by name.
+func newSource(name, sshAddr, sshUser, sshPass, sshKnownHosts, snmpAddr, snmpCommunity string) (nat.Source, error) {
+	switch name {
+	case "text":
+		return nat.NewTextSource(os.Stdin), nil
+	case "ssh":
+		if sshAddr == "" || sshUser == "" || sshKnownHosts == "" {
+			return nil, fmt.Errorf("-ssh-addr, -ssh-user, and -ssh-known-hosts are required for -source=ssh")
+		}
+		return nat.NewSSHSource(sshAddr, sshUser, sshPass, sshKnownHosts)
+	case "snmp":
+		if snmpAddr == "" {
+			return nil, fmt.Errorf("-snmp-addr is required for -source=snmp")
+		}
+		return nat.NewSNMPSource(snmpAddr, snmpCommunity)
+	default:
+		return nil, fmt.Errorf("unknown source %q", name)
+	}
 }
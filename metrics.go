@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ThisGuyCodes/cisco/nat"
+)
+
+// timeoutBucketBounds are the upper bounds (in seconds) of the
+// cisco_nat_timeout_seconds histogram buckets.
+var timeoutBucketBounds = []float64{60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400}
+
+var (
+	translationsDesc = prometheus.NewDesc(
+		"cisco_nat_translations",
+		"Number of active NAT translations.",
+		[]string{"proto"}, nil,
+	)
+	translationsLongDesc = prometheus.NewDesc(
+		"cisco_nat_translations_long",
+		"Number of active NAT translations with more than the configured threshold of time left.",
+		[]string{"proto"}, nil,
+	)
+	timeoutSecondsDesc = prometheus.NewDesc(
+		"cisco_nat_timeout_seconds",
+		"Distribution of configured NAT translation timeouts.",
+		[]string{"proto"}, nil,
+	)
+	insideHostDesc = prometheus.NewDesc(
+		"cisco_nat_translations_by_inside_host",
+		"Number of active NAT translations per inside-local host.",
+		[]string{"inside_local"}, nil,
+	)
+)
+
+// natCollector is a prometheus.Collector that scrapes a fresh nat.Source on
+// every /metrics request, so it works equally well backed by SSH, SNMP, or
+// a file source.
+type natCollector struct {
+	newSource     func() (nat.Source, error)
+	longThreshold time.Duration
+}
+
+func (c *natCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- translationsDesc
+	ch <- translationsLongDesc
+	ch <- timeoutSecondsDesc
+	ch <- insideHostDesc
+}
+
+func (c *natCollector) Collect(ch chan<- prometheus.Metric) {
+	src, err := c.newSource()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(translationsDesc, err)
+		return
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	counts := make(map[nat.NATProto]int)
+	longCounts := make(map[nat.NATProto]int)
+	timeouts := make(map[nat.NATProto][]float64)
+	insideHosts := make(map[string]int)
+
+	for {
+		n, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(translationsDesc, err)
+			return
+		}
+
+		counts[n.Proto]++
+		if n.Timeout > c.longThreshold {
+			longCounts[n.Proto]++
+		}
+		timeouts[n.Proto] = append(timeouts[n.Proto], n.Timeout.Seconds())
+		if n.InsideLocal != nil {
+			insideHosts[n.InsideLocal.String()]++
+		}
+	}
+
+	for proto, name := range nat.NAT_NAMES {
+		ch <- prometheus.MustNewConstMetric(translationsDesc, prometheus.GaugeValue, float64(counts[proto]), name)
+		ch <- prometheus.MustNewConstMetric(translationsLongDesc, prometheus.GaugeValue, float64(longCounts[proto]), name)
+
+		count, sum, buckets := histogramFor(timeouts[proto])
+		ch <- prometheus.MustNewConstHistogram(timeoutSecondsDesc, count, sum, buckets, name)
+	}
+
+	for host, n := range insideHosts {
+		ch <- prometheus.MustNewConstMetric(insideHostDesc, prometheus.GaugeValue, float64(n), host)
+	}
+}
+
+// histogramFor buckets samples (in seconds) against timeoutBucketBounds,
+// returning the totals prometheus.MustNewConstHistogram expects.
+func histogramFor(samples []float64) (count uint64, sum float64, buckets map[float64]uint64) {
+	buckets = make(map[float64]uint64, len(timeoutBucketBounds))
+
+	for _, s := range samples {
+		count++
+		sum += s
+	}
+
+	for _, bound := range timeoutBucketBounds {
+		var c uint64
+		for _, s := range samples {
+			if s <= bound {
+				c++
+			}
+		}
+		buckets[bound] = c
+	}
+
+	return count, sum, buckets
+}
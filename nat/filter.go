@@ -0,0 +1,616 @@
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompileFilter parses a filter expression and compiles it into a predicate
+// suitable for NATS.Where.
+//
+// Grammar (informally):
+//
+//	expr       := orExpr
+//	orExpr     := andExpr ( "||" andExpr )*
+//	andExpr    := unary ( "&&" unary )*
+//	unary      := "!" unary | comparison | "(" expr ")"
+//	comparison := field op value
+//	op         := "=" | "!=" | "<" | "<=" | ">" | ">=" | "in"
+//
+// field is any NAT struct member in snake_case (proto, inside_local,
+// inside_local_port, outside_global_port, timeout, created, used, ...).
+// value is a bare token: a proto name, an IP, a CIDR (with "in"), a
+// duration ("1h30m"), a port or port range/set ("8080", "8000-9000",
+// "{80,443}"), or an RFC3339 timestamp.
+//
+// Example: proto=tcp && timeout>1h && inside_local in 10.0.0.0/8 && outside_global_port in {80,443}
+func CompileFilter(expr string) (func(*NAT) bool, error) {
+	p := &filterParser{lex: newFilterLexer(expr)}
+	p.next()
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected token %q", p.tok.text)
+	}
+	return e, nil
+}
+
+// --- lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokEq
+	tokNeq
+	tokLt
+	tokLe
+	tokGt
+	tokGe
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// identStop holds the characters that end a bare value/field token. Values
+// like IPs, CIDRs, durations, and port sets ("10.0.0.0/8", "1h30m",
+// "{80,443}") are made of everything else, so they're read as one token.
+const identStop = " \t\n\r()!&|<>="
+
+type filterLexer struct {
+	input string
+	pos   int
+}
+
+func newFilterLexer(input string) *filterLexer {
+	return &filterLexer{input: input}
+}
+
+func (l *filterLexer) next() token {
+	for l.pos < len(l.input) && strings.ContainsRune(" \t\n\r", rune(l.input[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF}
+	}
+
+	rest := l.input[l.pos:]
+	switch {
+	case strings.HasPrefix(rest, "&&"):
+		l.pos += 2
+		return token{kind: tokAnd, text: "&&"}
+	case strings.HasPrefix(rest, "||"):
+		l.pos += 2
+		return token{kind: tokOr, text: "||"}
+	case strings.HasPrefix(rest, "!="):
+		l.pos += 2
+		return token{kind: tokNeq, text: "!="}
+	case strings.HasPrefix(rest, "<="):
+		l.pos += 2
+		return token{kind: tokLe, text: "<="}
+	case strings.HasPrefix(rest, ">="):
+		l.pos += 2
+		return token{kind: tokGe, text: ">="}
+	case rest[0] == '!':
+		l.pos++
+		return token{kind: tokNot, text: "!"}
+	case rest[0] == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "("}
+	case rest[0] == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")"}
+	case rest[0] == '=':
+		l.pos++
+		return token{kind: tokEq, text: "="}
+	case rest[0] == '<':
+		l.pos++
+		return token{kind: tokLt, text: "<"}
+	case rest[0] == '>':
+		l.pos++
+		return token{kind: tokGt, text: ">"}
+	default:
+		start := l.pos
+		for l.pos < len(l.input) && !strings.ContainsRune(identStop, rune(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.input[start:l.pos]}
+	}
+}
+
+// --- parser ---
+
+type filterParser struct {
+	lex *filterLexer
+	tok token
+}
+
+func (p *filterParser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *filterParser) parseOr() (func(*NAT) bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		prev := left
+		left = func(n *NAT) bool { return prev(n) || right(n) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (func(*NAT) bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		prev := left
+		left = func(n *NAT) bool { return prev(n) && right(n) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (func(*NAT) bool, error) {
+	if p.tok.kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n *NAT) bool { return !inner(n) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (func(*NAT) bool, error) {
+	if p.tok.kind == tokLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')', got %q", p.tok.text)
+		}
+		p.next()
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (func(*NAT) bool, error) {
+	if p.tok.kind != tokIdent {
+		return nil, fmt.Errorf("expected a field name, got %q", p.tok.text)
+	}
+	fieldName := p.tok.text
+	p.next()
+
+	op, err := p.parseOp()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokIdent {
+		return nil, fmt.Errorf("expected a value after %q %s, got %q", fieldName, op, p.tok.text)
+	}
+	value := p.tok.text
+	p.next()
+
+	return buildComparison(fieldName, op, value)
+}
+
+func (p *filterParser) parseOp() (string, error) {
+	switch p.tok.kind {
+	case tokEq:
+		p.next()
+		return "=", nil
+	case tokNeq:
+		p.next()
+		return "!=", nil
+	case tokLt:
+		p.next()
+		return "<", nil
+	case tokLe:
+		p.next()
+		return "<=", nil
+	case tokGt:
+		p.next()
+		return ">", nil
+	case tokGe:
+		p.next()
+		return ">=", nil
+	case tokIdent:
+		if p.tok.text == "in" {
+			p.next()
+			return "in", nil
+		}
+	}
+	return "", fmt.Errorf("expected an operator, got %q", p.tok.text)
+}
+
+// --- fields ---
+
+type fieldKind int
+
+const (
+	kindProto fieldKind = iota
+	kindIP
+	kindPort
+	kindDuration
+	kindTime
+	kindString
+	kindUint
+	kindMAC
+	kindFlags
+)
+
+type fieldSpec struct {
+	kind fieldKind
+	get  func(*NAT) interface{}
+}
+
+var filterFields = map[string]fieldSpec{
+	"proto":               {kindProto, func(n *NAT) interface{} { return n.Proto }},
+	"inside_global":       {kindIP, func(n *NAT) interface{} { return n.InsideGlobal }},
+	"inside_global_port":  {kindPort, func(n *NAT) interface{} { return n.InsideGlobalPort }},
+	"inside_local":        {kindIP, func(n *NAT) interface{} { return n.InsideLocal }},
+	"inside_local_port":   {kindPort, func(n *NAT) interface{} { return n.InsideLocalPort }},
+	"outside_local":       {kindIP, func(n *NAT) interface{} { return n.OutsideLocal }},
+	"outside_local_port":  {kindPort, func(n *NAT) interface{} { return n.OutsideLocalPort }},
+	"outside_global":      {kindIP, func(n *NAT) interface{} { return n.OutsideGlobal }},
+	"outside_global_port": {kindPort, func(n *NAT) interface{} { return n.OutsideGlobalPort }},
+	"created":             {kindTime, func(n *NAT) interface{} { return n.Created }},
+	"used":                {kindTime, func(n *NAT) interface{} { return n.Used }},
+	"timeout":             {kindDuration, func(n *NAT) interface{} { return n.Timeout }},
+	"flags":               {kindFlags, func(n *NAT) interface{} { return n.Flags }},
+	"mac":                 {kindMAC, func(n *NAT) interface{} { return n.MAC }},
+	"input_interface":     {kindString, func(n *NAT) interface{} { return n.InputInterface }},
+	"entry_id":            {kindUint, func(n *NAT) interface{} { return n.EntryID }},
+	"use_count":           {kindUint, func(n *NAT) interface{} { return n.UseCount }},
+	"vrf":                 {kindString, func(n *NAT) interface{} { return n.VRF }},
+}
+
+func buildComparison(name, op, value string) (func(*NAT) bool, error) {
+	field, ok := filterFields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+
+	switch field.kind {
+	case kindProto:
+		return buildProtoComparison(field.get, op, value)
+	case kindIP:
+		return buildIPComparison(field.get, op, value)
+	case kindPort:
+		return buildPortComparison(field.get, op, value)
+	case kindDuration:
+		return buildDurationComparison(field.get, op, value)
+	case kindTime:
+		return buildTimeComparison(field.get, op, value)
+	case kindString:
+		return buildStringComparison(field.get, op, value)
+	case kindUint:
+		return buildUintComparison(field.get, op, value)
+	case kindMAC:
+		return buildMACComparison(field.get, op, value)
+	case kindFlags:
+		return buildFlagsComparison(field.get, op, value)
+	default:
+		return nil, fmt.Errorf("field %q has no comparable kind", name)
+	}
+}
+
+func buildProtoComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	toProto := func(s string) (NATProto, error) {
+		p, ok := NAT_REVERSE_NAMES[s]
+		if !ok {
+			return 0, fmt.Errorf("unknown proto %q", s)
+		}
+		return p, nil
+	}
+
+	switch op {
+	case "=", "!=":
+		want, err := toProto(value)
+		if err != nil {
+			return nil, err
+		}
+		eq := op == "="
+		return func(n *NAT) bool { return (get(n).(NATProto) == want) == eq }, nil
+	case "in":
+		names, err := parseStringSet(value)
+		if err != nil {
+			return nil, err
+		}
+		wanted := make(map[NATProto]bool, len(names))
+		for _, s := range names {
+			p, err := toProto(s)
+			if err != nil {
+				return nil, err
+			}
+			wanted[p] = true
+		}
+		return func(n *NAT) bool { return wanted[get(n).(NATProto)] }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for proto fields", op)
+	}
+}
+
+func buildIPComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	switch op {
+	case "=", "!=":
+		want := net.ParseIP(value)
+		if want == nil {
+			return nil, fmt.Errorf("invalid IP %q", value)
+		}
+		eq := op == "="
+		return func(n *NAT) bool {
+			ip, _ := get(n).(net.IP)
+			return (ip != nil && ip.Equal(want)) == eq
+		}, nil
+	case "in":
+		_, cidr, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+		}
+		return func(n *NAT) bool {
+			ip, _ := get(n).(net.IP)
+			return ip != nil && cidr.Contains(ip)
+		}, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for IP fields", op)
+	}
+}
+
+func buildPortComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	if op == "in" {
+		if strings.HasPrefix(value, "{") {
+			items, err := parseStringSet(value)
+			if err != nil {
+				return nil, err
+			}
+			wanted := make(map[int]bool, len(items))
+			for _, item := range items {
+				port, err := strconv.Atoi(item)
+				if err != nil {
+					return nil, fmt.Errorf("invalid port %q", item)
+				}
+				wanted[port] = true
+			}
+			return func(n *NAT) bool { return wanted[get(n).(int)] }, nil
+		}
+
+		lo, hi, err := parseIntRange(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(n *NAT) bool {
+			port := get(n).(int)
+			return port >= lo && port <= hi
+		}, nil
+	}
+
+	want, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", value)
+	}
+	return compareInt(get, op, want)
+}
+
+func compareInt(get func(*NAT) interface{}, op string, want int) (func(*NAT) bool, error) {
+	switch op {
+	case "=":
+		return func(n *NAT) bool { return get(n).(int) == want }, nil
+	case "!=":
+		return func(n *NAT) bool { return get(n).(int) != want }, nil
+	case "<":
+		return func(n *NAT) bool { return get(n).(int) < want }, nil
+	case "<=":
+		return func(n *NAT) bool { return get(n).(int) <= want }, nil
+	case ">":
+		return func(n *NAT) bool { return get(n).(int) > want }, nil
+	case ">=":
+		return func(n *NAT) bool { return get(n).(int) >= want }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for numeric fields", op)
+	}
+}
+
+func buildDurationComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	want, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %w", value, err)
+	}
+	switch op {
+	case "=":
+		return func(n *NAT) bool { return get(n).(time.Duration) == want }, nil
+	case "!=":
+		return func(n *NAT) bool { return get(n).(time.Duration) != want }, nil
+	case "<":
+		return func(n *NAT) bool { return get(n).(time.Duration) < want }, nil
+	case "<=":
+		return func(n *NAT) bool { return get(n).(time.Duration) <= want }, nil
+	case ">":
+		return func(n *NAT) bool { return get(n).(time.Duration) > want }, nil
+	case ">=":
+		return func(n *NAT) bool { return get(n).(time.Duration) >= want }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for duration fields", op)
+	}
+}
+
+func buildTimeComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	want, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q (want RFC3339): %w", value, err)
+	}
+	switch op {
+	case "=":
+		return func(n *NAT) bool { return get(n).(time.Time).Equal(want) }, nil
+	case "!=":
+		return func(n *NAT) bool { return !get(n).(time.Time).Equal(want) }, nil
+	case "<":
+		return func(n *NAT) bool { return get(n).(time.Time).Before(want) }, nil
+	case "<=":
+		return func(n *NAT) bool { return !get(n).(time.Time).After(want) }, nil
+	case ">":
+		return func(n *NAT) bool { return get(n).(time.Time).After(want) }, nil
+	case ">=":
+		return func(n *NAT) bool { return !get(n).(time.Time).Before(want) }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for time fields", op)
+	}
+}
+
+func buildStringComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	switch op {
+	case "=", "!=":
+		eq := op == "="
+		return func(n *NAT) bool { return (get(n).(string) == value) == eq }, nil
+	case "in":
+		items, err := parseStringSet(value)
+		if err != nil {
+			return nil, err
+		}
+		wanted := make(map[string]bool, len(items))
+		for _, s := range items {
+			wanted[s] = true
+		}
+		return func(n *NAT) bool { return wanted[get(n).(string)] }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for string fields", op)
+	}
+}
+
+func buildUintComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	want, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q: %w", value, err)
+	}
+	switch op {
+	case "=":
+		return func(n *NAT) bool { return get(n).(uint64) == want }, nil
+	case "!=":
+		return func(n *NAT) bool { return get(n).(uint64) != want }, nil
+	case "<":
+		return func(n *NAT) bool { return get(n).(uint64) < want }, nil
+	case "<=":
+		return func(n *NAT) bool { return get(n).(uint64) <= want }, nil
+	case ">":
+		return func(n *NAT) bool { return get(n).(uint64) > want }, nil
+	case ">=":
+		return func(n *NAT) bool { return get(n).(uint64) >= want }, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for numeric fields", op)
+	}
+}
+
+func buildMACComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	want, err := net.ParseMAC(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC address %q: %w", value, err)
+	}
+	switch op {
+	case "=", "!=":
+		eq := op == "="
+		return func(n *NAT) bool {
+			mac, _ := get(n).(net.HardwareAddr)
+			return (mac != nil && mac.String() == want.String()) == eq
+		}, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for MAC fields", op)
+	}
+}
+
+// buildFlagsComparison builds a predicate over a []string field (currently
+// just Flags), where "=" and "!=" test for membership of a single flag and
+// "in" tests for membership of any flag in a set.
+func buildFlagsComparison(get func(*NAT) interface{}, op, value string) (func(*NAT) bool, error) {
+	switch op {
+	case "=", "!=":
+		eq := op == "="
+		return func(n *NAT) bool { return containsString(get(n).([]string), value) == eq }, nil
+	case "in":
+		items, err := parseStringSet(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(n *NAT) bool {
+			flags := get(n).([]string)
+			for _, item := range items {
+				if containsString(flags, item) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	default:
+		return nil, fmt.Errorf("operator %q not supported for flags fields", op)
+	}
+}
+
+// containsString reports whether s appears in list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStringSet parses a "{a,b,c}" token into its comma-separated items.
+func parseStringSet(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "{") || !strings.HasSuffix(value, "}") {
+		return nil, fmt.Errorf("expected a set like {a,b,c}, got %q", value)
+	}
+	inner := value[1 : len(value)-1]
+	if inner == "" {
+		return nil, nil
+	}
+	return strings.Split(inner, ","), nil
+}
+
+// parseIntRange parses a "low-high" token into its bounds.
+func parseIntRange(value string) (lo, hi int, err error) {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected a range like 8000-9000, got %q", value)
+	}
+	lo, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range start %q", parts[0])
+	}
+	hi, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range end %q", parts[1])
+	}
+	return lo, hi, nil
+}
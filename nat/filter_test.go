@@ -0,0 +1,143 @@
+package nat
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func sampleNAT(t *testing.T) *NAT {
+	t.Helper()
+	return &NAT{
+		Proto:             TCP_NAT,
+		InsideGlobal:      net.ParseIP("172.16.131.1"),
+		InsideGlobalPort:  41751,
+		InsideLocal:       net.ParseIP("192.168.1.95"),
+		InsideLocalPort:   41751,
+		OutsideLocal:      net.ParseIP("172.16.131.254"),
+		OutsideLocalPort:  23,
+		OutsideGlobal:     net.ParseIP("172.16.131.254"),
+		OutsideGlobalPort: 23,
+		Created:           mustParseDate(t, "01/02/06 15:04:05"),
+		Used:              mustParseDate(t, "01/02/06 15:05:00"),
+		Timeout:           time.Hour,
+		Flags:             []string{"extended", "ESTAB"},
+		MAC:               mustParseMAC(t, "00:0c:29:3b:5a:1f"),
+		InputInterface:    "Vlan10",
+		EntryID:           6,
+		UseCount:          1,
+		VRF:               "CUSTOMER_A",
+	}
+}
+
+func TestCompileFilterMatch(t *testing.T) {
+	n := sampleNAT(t)
+
+	cases := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		// proto
+		{"proto eq match", "proto=tcp", true},
+		{"proto eq mismatch", "proto=udp", false},
+		{"proto neq", "proto!=udp", true},
+		{"proto in", "proto in {udp,tcp}", true},
+		{"proto in mismatch", "proto in {udp,icmp}", false},
+
+		// IP
+		{"ip eq", "inside_local=192.168.1.95", true},
+		{"ip neq", "inside_local!=10.0.0.1", true},
+		{"ip in cidr", "inside_local in 192.168.1.0/24", true},
+		{"ip in cidr mismatch", "inside_local in 10.0.0.0/8", false},
+
+		// port
+		{"port eq", "inside_local_port=41751", true},
+		{"port gt", "outside_local_port>10", true},
+		{"port in set", "outside_local_port in {22,23,80}", true},
+		{"port in range", "outside_local_port in 1-1024", true},
+		{"port in range mismatch", "outside_local_port in 1025-2000", false},
+
+		// duration
+		{"timeout eq", "timeout=1h", true},
+		{"timeout gt", "timeout>30m", true},
+		{"timeout lt", "timeout<30m", false},
+
+		// time
+		{"created eq", "created=2006-01-02T15:04:05Z", true},
+		{"used gt", "used>2006-01-02T15:04:05Z", true},
+
+		// string
+		{"vrf eq", "vrf=CUSTOMER_A", true},
+		{"vrf neq", "vrf!=CUSTOMER_B", true},
+		{"vrf in", "vrf in {CUSTOMER_A,CUSTOMER_B}", true},
+		{"input_interface eq", "input_interface=Vlan10", true},
+
+		// uint
+		{"entry_id eq", "entry_id=6", true},
+		{"use_count gt", "use_count>0", true},
+		{"use_count lt", "use_count<1", false},
+
+		// MAC
+		{"mac eq", "mac=00:0c:29:3b:5a:1f", true},
+		{"mac neq", "mac!=00:00:00:00:00:00", true},
+
+		// flags
+		{"flags eq present", "flags=ESTAB", true},
+		{"flags eq absent", "flags=bogus", false},
+		{"flags neq absent", "flags!=bogus", true},
+		{"flags in", "flags in {bogus,ESTAB}", true},
+		{"flags in mismatch", "flags in {bogus,other}", false},
+
+		// composition
+		{"and", "proto=tcp && vrf=CUSTOMER_A", true},
+		{"or", "proto=udp || vrf=CUSTOMER_A", true},
+		{"not", "!(proto=udp)", true},
+		{"parens", "(proto=tcp && vrf=CUSTOMER_A) || proto=udp", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pred, err := CompileFilter(c.expr)
+			if err != nil {
+				t.Fatalf("CompileFilter(%q): %v", c.expr, err)
+			}
+			if got := pred(n); got != c.want {
+				t.Errorf("CompileFilter(%q)(n) = %v, want %v", c.expr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		expr string
+	}{
+		{"unknown field", "bogus_field=1"},
+		{"bad cidr", "inside_local in 10.0.0.0/40"},
+		{"bad duration", "timeout>not-a-duration"},
+		{"bad timestamp", "created>not-a-timestamp"},
+		{"malformed set", "proto in udp,tcp}"},
+		{"malformed range", "outside_local_port in 1-"},
+		{"unbalanced open paren", "(proto=tcp"},
+		{"unbalanced close paren", "proto=tcp)"},
+		{"missing operator", "proto tcp"},
+		{"missing value", "proto="},
+		{"unknown proto", "proto=bogus"},
+		{"invalid mac", "mac=not-a-mac"},
+		{"unsupported op for flags", "flags>ESTAB"},
+		{"unsupported op for string", "vrf>CUSTOMER_A"},
+		{"unsupported op for mac in", "mac in {00:0c:29:3b:5a:1f}"},
+		{"invalid uint", "entry_id=-1"},
+		{"trailing garbage", "proto=tcp proto=udp"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := CompileFilter(c.expr); err == nil {
+				t.Errorf("CompileFilter(%q): expected an error, got none", c.expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package nat parses and represents Cisco IOS NAT translation records.
+package nat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type NAT struct {
+	Proto             NATProto
+	InsideGlobal      net.IP
+	InsideGlobalPort  int
+	InsideLocal       net.IP
+	InsideLocalPort   int
+	OutsideLocal      net.IP
+	OutsideLocalPort  int
+	OutsideGlobal     net.IP
+	OutsideGlobalPort int
+	Created           time.Time
+	Used              time.Time
+	Timeout           time.Duration
+
+	// The following are only populated from `show ip nat translations
+	// verbose`/`extended` output; TextSource/SSHSource readers of the terse
+	// form leave them at their zero value.
+	Flags          []string
+	MAC            net.HardwareAddr
+	InputInterface string
+	EntryID        uint64
+	UseCount       uint64
+	VRF            string
+}
+
+type NATS []*NAT
+
+func (nats NATS) Where(fn func(*NAT) bool) NATS {
+	filtered := make(NATS, 0)
+	for _, nat := range nats {
+		if fn(nat) {
+			filtered = append(filtered, nat)
+		}
+	}
+	return filtered
+}
+
+type NATProto uint8
+
+func (nat NATProto) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NAT_NAMES[nat])
+}
+
+func (nat *NATProto) UnmarshalJSON(data []byte) error {
+	var dest string
+	err := json.Unmarshal(data, &dest)
+	*nat = NAT_REVERSE_NAMES[dest]
+	return err
+}
+
+const (
+	UDP_NAT NATProto = iota
+	TCP_NAT
+	STATIC_NAT
+	ICMP_NAT
+
+	DATE_FORMAT = "01/02/06 15:04:05"
+)
+
+var (
+	ROUTE_SEP    = []byte("\n\n")
+	ROUTE_HEADER = []byte("Pro")
+
+	// The trailing group is only present under `show ip nat translations
+	// extended`, which appends a VRF column.
+	ROUTE_REGEXP = regexp.MustCompile(`^(-{3}|tcp|udp|icmp)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)\s+([\-\:0-9\.]+)(?:\s+(\S+))?$`)
+
+	// Not anchored at the end of the line: `verbose` output appends
+	// further comma-separated fields (flags, use_count, ...) after the
+	// timeout that EXTRA_FIELD_REGEXP picks up separately.
+	TIME_REGEXP     = regexp.MustCompile(`^\s+create:\s+([^,]+),\s+use:\s+([^,]+),\s+timeout:\s+([^,]+)`)
+	DURATION_REGEXP = regexp.MustCompile(`(\d\d):(\d\d):(\d\d)`)
+
+	// EXTRA_FIELD_REGEXP picks the fields `verbose` output adds on
+	// continuation lines after the route and time lines.
+	EXTRA_FIELD_REGEXP = regexp.MustCompile(`(Flags|Mac-Address|Input-IDB|entry-id|use_count|VRF):\s*([^,]+)`)
+
+	DURATION_REPLACE = []byte(`${1}h${2}m${3}s`)
+
+	NAT_TRANSLATION = map[byte]NATProto{
+		byte('u'): UDP_NAT,
+		byte('t'): TCP_NAT,
+		byte('-'): STATIC_NAT,
+		byte('i'): ICMP_NAT,
+	}
+
+	NAT_NAMES = map[NATProto]string{
+		UDP_NAT:    "udp",
+		TCP_NAT:    "tcp",
+		STATIC_NAT: "static",
+		ICMP_NAT:   "icmp",
+	}
+	NAT_REVERSE_NAMES = reverseNATNames(NAT_NAMES)
+)
+
+func reverseNATNames(names map[NATProto]string) map[string]NATProto {
+	reversed := make(map[string]NATProto, len(names))
+	for key, value := range names {
+		reversed[value] = key
+	}
+	return reversed
+}
+
+// routeSplitFunc is a bufio.SplitFunc that splits a stream of
+// `show ip nat translations` output into individual records, stripping the
+// header line if present.
+func routeSplitFunc(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 && atEOF {
+		return 0, nil, nil
+	}
+	from, to, advance := 0, 0, 0
+
+	i := bytes.Index(data, ROUTE_SEP)
+	if i == -1 {
+		if !atEOF {
+			// We don't have a whole route, request more data
+			return 0, nil, nil
+		} else {
+			if bytes.HasSuffix(data, []byte("\n")) {
+				// Ends in a newline at EOF, we're done
+				return len(data), data[:len(data)-1], nil
+			} else {
+				return 0, nil, errors.New("Improperly formatted file: it must end with an empty line")
+			}
+		}
+	} else {
+		to = i
+		// We want to omit the seperator
+		advance = i + len(ROUTE_SEP)
+	}
+
+	if bytes.HasPrefix(data, ROUTE_HEADER) {
+		// This includes the header, we need to omit it
+		// Find the end of the header line, and omit the newline character
+		from = bytes.Index(data, []byte("\n")) + 1
+	}
+
+	return advance, data[from:to], nil
+}
+
+// Parse fills in nat from a single record as produced by routeSplitFunc: a
+// route line, a time line, and under `verbose`/`extended` output, however
+// many further continuation lines IOS chose to print. The record ends at
+// the \n\n separator routeSplitFunc already strips, so Parse makes no
+// assumption about how many lines follow the first two.
+func (nat *NAT) Parse(data []byte) error {
+	var err error
+	lines := bytes.Split(data, []byte("\n"))
+	if len(lines) < 2 {
+		return fmt.Errorf("expected at least a route line and a time line, got %d line(s)", len(lines))
+	}
+
+	ips := ROUTE_REGEXP.FindSubmatch(lines[0])
+	if ips == nil {
+		return fmt.Errorf("could not parse route line: %q", lines[0])
+	}
+
+	nat.Proto = NAT_TRANSLATION[ips[1][0]]
+
+	if nat.Proto == STATIC_NAT {
+		nat.InsideGlobal = net.ParseIP(string(ips[2]))
+		nat.InsideLocal = net.ParseIP(string(ips[3]))
+		nat.OutsideLocal = net.ParseIP(string(ips[4]))
+		nat.OutsideGlobal = net.ParseIP(string(ips[5]))
+	} else {
+		nat.InsideGlobal, nat.InsideGlobalPort, err = parseIpPort(ips[2], "Inside Global")
+		if err != nil {
+			return err
+		}
+
+		nat.InsideLocal, nat.InsideLocalPort, err = parseIpPort(ips[3], "Inside Local")
+		if err != nil {
+			return err
+		}
+
+		nat.OutsideLocal, nat.OutsideLocalPort, err = parseIpPort(ips[4], "Outside Local")
+		if err != nil {
+			return err
+		}
+
+		nat.OutsideGlobal, nat.OutsideGlobalPort, err = parseIpPort(ips[5], "Outside Global")
+		if err != nil {
+			return err
+		}
+	}
+	if vrf := ips[6]; len(vrf) > 0 {
+		nat.VRF = string(vrf)
+	}
+
+	times := TIME_REGEXP.FindSubmatch(lines[1])
+	if times == nil {
+		return fmt.Errorf("could not parse time line: %q", lines[1])
+	}
+
+	nat.Created, err = time.Parse(DATE_FORMAT, string(times[1]))
+	if err != nil {
+		return err
+	}
+
+	nat.Used, err = time.Parse(DATE_FORMAT, string(times[2]))
+	if err != nil {
+		return err
+	}
+
+	timeout := DURATION_REGEXP.ReplaceAll(bytes.TrimSpace(times[3]), DURATION_REPLACE)
+	nat.Timeout, err = time.ParseDuration(string(timeout))
+	if err != nil {
+		return err
+	}
+
+	// Everything from the rest of the time line onward (verbose adds
+	// trailing fields there too) through any further continuation lines.
+	return nat.parseExtraFields(bytes.Join(lines[1:], []byte(" ")))
+}
+
+// parseExtraFields fills in the fields `verbose`/`extended` output adds,
+// found anywhere in data as "Key: value" pairs. Unrecognized keys are
+// ignored, so unfamiliar IOS additions don't break parsing.
+func (nat *NAT) parseExtraFields(data []byte) error {
+	for _, m := range EXTRA_FIELD_REGEXP.FindAllSubmatch(data, -1) {
+		value := string(bytes.TrimSpace(m[2]))
+
+		switch string(m[1]) {
+		case "Flags":
+			nat.Flags = strings.Fields(value)
+		case "Mac-Address":
+			mac, err := net.ParseMAC(value)
+			if err != nil {
+				return fmt.Errorf("parsing Mac-Address: %w", err)
+			}
+			nat.MAC = mac
+		case "Input-IDB":
+			nat.InputInterface = value
+		case "entry-id":
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing entry-id: %w", err)
+			}
+			nat.EntryID = id
+		case "use_count":
+			count, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing use_count: %w", err)
+			}
+			nat.UseCount = count
+		case "VRF":
+			nat.VRF = value
+		}
+	}
+	return nil
+}
+
+func parseIpPort(data []byte, name string) (net.IP, int, error) {
+	host, port, err := net.SplitHostPort(string(data))
+	if err != nil {
+		errStr := fmt.Sprintf("Could not parse %s address: %s", name, err)
+		return nil, 0, errors.New(errStr)
+	}
+
+	hostIp := net.ParseIP(host)
+	hostPort, err := strconv.Atoi(port)
+
+	if err != nil {
+		errStr := fmt.Sprintf("Could not parse %s port: %s", name, err)
+		return hostIp, 0, errors.New(errStr)
+	}
+
+	return hostIp, hostPort, nil
+}
@@ -0,0 +1,142 @@
+package nat
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want *NAT
+	}{
+		{
+			name: "terse",
+			data: "tcp 172.16.131.1:41751 192.168.1.95:41751 172.16.131.254:23 172.16.131.254:23\n" +
+				"    create: 01/02/06 15:04:05, use: 01/02/06 15:05:00, timeout: 01:00:00",
+			want: &NAT{
+				Proto:             TCP_NAT,
+				InsideGlobal:      net.ParseIP("172.16.131.1"),
+				InsideGlobalPort:  41751,
+				InsideLocal:       net.ParseIP("192.168.1.95"),
+				InsideLocalPort:   41751,
+				OutsideLocal:      net.ParseIP("172.16.131.254"),
+				OutsideLocalPort:  23,
+				OutsideGlobal:     net.ParseIP("172.16.131.254"),
+				OutsideGlobalPort: 23,
+				Created:           mustParseDate(t, "01/02/06 15:04:05"),
+				Used:              mustParseDate(t, "01/02/06 15:05:00"),
+				Timeout:           time.Hour,
+			},
+		},
+		{
+			name: "verbose",
+			data: "tcp 172.16.131.1:41751 192.168.1.95:41751 172.16.131.254:23 172.16.131.254:23\n" +
+				"    create: 01/02/06 15:04:05, use: 01/02/06 15:05:00, timeout: 01:00:00, Flags: extended, entry-id: 6, use_count: 1,\n" +
+				"Mac-Address: 00:0c:29:3b:5a:1f, Input-IDB: Vlan10, VRF: CUSTOMER_A",
+			want: &NAT{
+				Proto:             TCP_NAT,
+				InsideGlobal:      net.ParseIP("172.16.131.1"),
+				InsideGlobalPort:  41751,
+				InsideLocal:       net.ParseIP("192.168.1.95"),
+				InsideLocalPort:   41751,
+				OutsideLocal:      net.ParseIP("172.16.131.254"),
+				OutsideLocalPort:  23,
+				OutsideGlobal:     net.ParseIP("172.16.131.254"),
+				OutsideGlobalPort: 23,
+				Created:           mustParseDate(t, "01/02/06 15:04:05"),
+				Used:              mustParseDate(t, "01/02/06 15:05:00"),
+				Timeout:           time.Hour,
+				Flags:             []string{"extended"},
+				EntryID:           6,
+				UseCount:          1,
+				MAC:               mustParseMAC(t, "00:0c:29:3b:5a:1f"),
+				InputInterface:    "Vlan10",
+				VRF:               "CUSTOMER_A",
+			},
+		},
+		{
+			name: "extended",
+			data: "tcp 172.16.131.1:41751 192.168.1.95:41751 172.16.131.254:23 172.16.131.254:23 CUSTOMER_A\n" +
+				"    create: 01/02/06 15:04:05, use: 01/02/06 15:05:00, timeout: 01:00:00",
+			want: &NAT{
+				Proto:             TCP_NAT,
+				InsideGlobal:      net.ParseIP("172.16.131.1"),
+				InsideGlobalPort:  41751,
+				InsideLocal:       net.ParseIP("192.168.1.95"),
+				InsideLocalPort:   41751,
+				OutsideLocal:      net.ParseIP("172.16.131.254"),
+				OutsideLocalPort:  23,
+				OutsideGlobal:     net.ParseIP("172.16.131.254"),
+				OutsideGlobalPort: 23,
+				Created:           mustParseDate(t, "01/02/06 15:04:05"),
+				Used:              mustParseDate(t, "01/02/06 15:05:00"),
+				Timeout:           time.Hour,
+				VRF:               "CUSTOMER_A",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := new(NAT)
+			if err := got.Parse([]byte(c.data)); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if !got.Created.Equal(c.want.Created) || !got.Used.Equal(c.want.Used) {
+				t.Fatalf("timestamps: got created=%v used=%v, want created=%v used=%v",
+					got.Created, got.Used, c.want.Created, c.want.Used)
+			}
+			got.Created, c.want.Created = time.Time{}, time.Time{}
+			got.Used, c.want.Used = time.Time{}, time.Time{}
+
+			if got.Proto != c.want.Proto ||
+				!got.InsideGlobal.Equal(c.want.InsideGlobal) || got.InsideGlobalPort != c.want.InsideGlobalPort ||
+				!got.InsideLocal.Equal(c.want.InsideLocal) || got.InsideLocalPort != c.want.InsideLocalPort ||
+				!got.OutsideLocal.Equal(c.want.OutsideLocal) || got.OutsideLocalPort != c.want.OutsideLocalPort ||
+				!got.OutsideGlobal.Equal(c.want.OutsideGlobal) || got.OutsideGlobalPort != c.want.OutsideGlobalPort ||
+				got.Timeout != c.want.Timeout ||
+				got.EntryID != c.want.EntryID ||
+				got.UseCount != c.want.UseCount ||
+				got.InputInterface != c.want.InputInterface ||
+				got.VRF != c.want.VRF ||
+				got.MAC.String() != c.want.MAC.String() ||
+				!stringSlicesEqual(got.Flags, c.want.Flags) {
+				t.Fatalf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func mustParseDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.Parse(DATE_FORMAT, s)
+	if err != nil {
+		t.Fatalf("parsing fixture date %q: %v", s, err)
+	}
+	return tm
+}
+
+func mustParseMAC(t *testing.T, s string) net.HardwareAddr {
+	t.Helper()
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		t.Fatalf("parsing fixture MAC %q: %v", s, err)
+	}
+	return mac
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,306 @@
+package nat
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// CISCO-IETF-NAT-MIB object identifiers used by SNMPSource.
+//
+// Rows in cnatAddrPortBindTable (PAT, port-level bindings) are indexed by
+// {protocol, localAddrType, localAddr, localPort, globalAddrType, globalAddr, globalPort} —
+// 1 + 1 + 4 + 1 + 1 + 4 + 1 = 13 dotted components once the table/column
+// prefix is stripped — so the protocol and idle-time columns below can be
+// walked independently and merged back together by that shared suffix.
+//
+// Rows in cnatAddrBindTable (address-only bindings, e.g. static NAT) are
+// indexed by just {localAddrType, localAddr}, with the global address
+// exposed as its own column.
+const (
+	oidCnatAddrBindNumberOfEntries = "1.3.6.1.4.1.9.10.77.1.1.1.0"
+	oidCnatAddrBindGlobalAddr      = "1.3.6.1.4.1.9.10.77.1.2.1.1.3"
+	oidCnatAddrBindCurrentIdleTime = "1.3.6.1.4.1.9.10.77.1.2.1.1.7"
+
+	oidCnatAddrPortBindProtocol        = "1.3.6.1.4.1.9.10.77.1.3.1.1.1"
+	oidCnatAddrPortBindCurrentIdleTime = "1.3.6.1.4.1.9.10.77.1.3.1.1.8"
+)
+
+// cnatProtocolType values, from CISCO-IETF-NAT-MIB.
+const (
+	cnatProtoOther = 1
+	cnatProtoUDP   = 2
+	cnatProtoTCP   = 3
+	cnatProtoICMP  = 4
+)
+
+// InetAddressType values (RFC 4001) used by the address-type components of
+// both tables' indexes. Only ipv4 is supported; rows using anything else
+// are rejected rather than silently mis-decoded.
+const inetAddressTypeIPv4 = 1
+
+var cnatProtoToNATProto = map[int]NATProto{
+	cnatProtoUDP:  UDP_NAT,
+	cnatProtoTCP:  TCP_NAT,
+	cnatProtoICMP: ICMP_NAT,
+}
+
+// SNMPSource walks CISCO-IETF-NAT-MIB on a target router and produces the
+// same *NAT values TextSource and SSHSource produce from CLI output, by
+// combining cnatAddrPortBindTable (PAT translations) and cnatAddrBindTable
+// (address-only/static bindings, reported as STATIC_NAT).
+//
+// The MIB has no equivalent of the CLI's "create" and "use" timestamps, or
+// of a configured timeout: the only lifetime signal it exposes is
+// cnatAddrBindCurrentIdleTime, how long the entry has sat idle since its
+// last use. SNMPSource reports that value as Timeout (so "long idle"
+// filters and alerts still work) and leaves Created and Used zero. Callers
+// that need the real timestamps should prefer TextSource or SSHSource.
+type SNMPSource struct {
+	entries []*NAT
+	next    int
+}
+
+// NewSNMPSource connects to addr (host:port) with the given SNMP community
+// and walks its NAT translation tables, returning a Source over the
+// results.
+func NewSNMPSource(addr, community string) (*SNMPSource, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing address %s: %w", addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("parsing port %s: %w", portStr, err)
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      uint16(port),
+		Community: community,
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second * 5,
+		Retries:   3,
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	defer client.Conn.Close()
+
+	var entries []*NAT
+
+	patEntries, err := walkAddrPortBindings(client)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, patEntries...)
+
+	staticCount, err := getInt(client, oidCnatAddrBindNumberOfEntries)
+	if err != nil {
+		return nil, fmt.Errorf("getting cnatAddrBindNumberOfEntries: %w", err)
+	}
+	if staticCount > 0 {
+		staticEntries, err := walkAddrBindings(client)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, staticEntries...)
+	}
+
+	return &SNMPSource{entries: entries}, nil
+}
+
+// walkAddrPortBindings walks cnatAddrPortBindTable, producing one *NAT per
+// row with its Proto and Timeout (from idle time) filled in. Rows whose
+// protocol has no NATProto counterpart (cnatProtoOther) are skipped.
+func walkAddrPortBindings(client *gosnmp.GoSNMP) ([]*NAT, error) {
+	idle := make(map[string]time.Duration)
+	err := client.BulkWalk(oidCnatAddrPortBindCurrentIdleTime, func(pdu gosnmp.SnmpPDU) error {
+		seconds := gosnmp.ToBigInt(pdu.Value).Int64()
+		idle[indexSuffix(oidCnatAddrPortBindCurrentIdleTime, pdu.Name)] = time.Duration(seconds) * time.Second
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking cnatAddrPortBindCurrentIdleTime: %w", err)
+	}
+
+	var entries []*NAT
+	err = client.BulkWalk(oidCnatAddrPortBindProtocol, func(pdu gosnmp.SnmpPDU) error {
+		suffix := indexSuffix(oidCnatAddrPortBindProtocol, pdu.Name)
+
+		indexProto, nat, err := parseCnatBindIndex(suffix)
+		if err != nil {
+			return fmt.Errorf("parsing index %s: %w", suffix, err)
+		}
+
+		columnProto := int(gosnmp.ToBigInt(pdu.Value).Int64())
+		if columnProto != indexProto {
+			return fmt.Errorf("cnatAddrPortBindProtocol column (%d) disagrees with its own index (%d) for %s", columnProto, indexProto, suffix)
+		}
+
+		proto, ok := cnatProtoToNATProto[columnProto]
+		if !ok {
+			// cnatProtoOther or an unrecognized value: nothing in NATProto
+			// maps onto it, so skip the row rather than guess.
+			return nil
+		}
+		nat.Proto = proto
+
+		if d, ok := idle[suffix]; ok {
+			nat.Timeout = d
+		}
+
+		entries = append(entries, nat)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking cnatAddrPortBindProtocol: %w", err)
+	}
+
+	return entries, nil
+}
+
+// walkAddrBindings walks cnatAddrBindTable, the address-only bindings table
+// (no port, e.g. static NAT), producing one STATIC_NAT *NAT per row.
+func walkAddrBindings(client *gosnmp.GoSNMP) ([]*NAT, error) {
+	idle := make(map[string]time.Duration)
+	err := client.BulkWalk(oidCnatAddrBindCurrentIdleTime, func(pdu gosnmp.SnmpPDU) error {
+		seconds := gosnmp.ToBigInt(pdu.Value).Int64()
+		idle[indexSuffix(oidCnatAddrBindCurrentIdleTime, pdu.Name)] = time.Duration(seconds) * time.Second
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking cnatAddrBindCurrentIdleTime: %w", err)
+	}
+
+	var entries []*NAT
+	err = client.BulkWalk(oidCnatAddrBindGlobalAddr, func(pdu gosnmp.SnmpPDU) error {
+		suffix := indexSuffix(oidCnatAddrBindGlobalAddr, pdu.Name)
+
+		localIP, err := parseCnatAddrBindIndex(suffix)
+		if err != nil {
+			return fmt.Errorf("parsing index %s: %w", suffix, err)
+		}
+
+		globalOctets, ok := pdu.Value.([]byte)
+		if !ok || len(globalOctets) != 4 {
+			return fmt.Errorf("unexpected cnatAddrBindGlobalAddr value for %s: %v", suffix, pdu.Value)
+		}
+		globalIP := net.IPv4(globalOctets[0], globalOctets[1], globalOctets[2], globalOctets[3])
+
+		nat := &NAT{
+			Proto:         STATIC_NAT,
+			InsideLocal:   localIP,
+			OutsideGlobal: globalIP,
+		}
+		if d, ok := idle[suffix]; ok {
+			nat.Timeout = d
+		}
+
+		entries = append(entries, nat)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking cnatAddrBindGlobalAddr: %w", err)
+	}
+
+	return entries, nil
+}
+
+// getInt fetches a single scalar OID and returns it as an int64.
+func getInt(client *gosnmp.GoSNMP, oid string) (int64, error) {
+	result, err := client.Get([]string{oid})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Variables) != 1 {
+		return 0, fmt.Errorf("expected exactly one variable for %s, got %d", oid, len(result.Variables))
+	}
+	return gosnmp.ToBigInt(result.Variables[0].Value).Int64(), nil
+}
+
+func (s *SNMPSource) Next() (*NAT, error) {
+	if s.next >= len(s.entries) {
+		return nil, io.EOF
+	}
+	nat := s.entries[s.next]
+	s.next++
+	return nat, nil
+}
+
+// indexSuffix strips the table/column OID prefix from a full varbind name,
+// leaving just the INDEX portion.
+func indexSuffix(oid, name string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(name, "."), oid+".")
+}
+
+// parseCnatBindIndex decodes a cnatAddrPortBindTable row index of the form
+// protocol.localAddrType.localAddr(4 octets).localPort.globalAddrType.globalAddr(4 octets).globalPort
+// returning the cnatProtocolType found in the index alongside a NAT with
+// the inside-local and outside-global fields filled in. This mirrors the
+// pairing the router itself makes between a translated host and its public
+// mapping; inside-global and outside-local are not carried in this table
+// and are left unset.
+func parseCnatBindIndex(suffix string) (int, *NAT, error) {
+	parts := strings.Split(suffix, ".")
+	if len(parts) != 13 {
+		return 0, nil, fmt.Errorf("unexpected index shape (want 13 components, got %d): %q", len(parts), suffix)
+	}
+
+	ints := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, nil, fmt.Errorf("non-numeric index component %q: %w", p, err)
+		}
+		ints[i] = n
+	}
+
+	proto := ints[0]
+	localAddrType := ints[1]
+	globalAddrType := ints[7]
+	if localAddrType != inetAddressTypeIPv4 || globalAddrType != inetAddressTypeIPv4 {
+		return 0, nil, fmt.Errorf("unsupported address type (local=%d, global=%d): only IPv4 is supported", localAddrType, globalAddrType)
+	}
+
+	localIP := net.IPv4(byte(ints[2]), byte(ints[3]), byte(ints[4]), byte(ints[5]))
+	localPort := ints[6]
+	globalIP := net.IPv4(byte(ints[8]), byte(ints[9]), byte(ints[10]), byte(ints[11]))
+	globalPort := ints[12]
+
+	return proto, &NAT{
+		InsideLocal:       localIP,
+		InsideLocalPort:   localPort,
+		OutsideGlobal:     globalIP,
+		OutsideGlobalPort: globalPort,
+	}, nil
+}
+
+// parseCnatAddrBindIndex decodes a cnatAddrBindTable row index of the form
+// localAddrType.localAddr(4 octets), returning the inside-local address.
+func parseCnatAddrBindIndex(suffix string) (net.IP, error) {
+	parts := strings.Split(suffix, ".")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("unexpected index shape (want 5 components, got %d): %q", len(parts), suffix)
+	}
+
+	ints := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("non-numeric index component %q: %w", p, err)
+		}
+		ints[i] = n
+	}
+
+	if ints[0] != inetAddressTypeIPv4 {
+		return nil, fmt.Errorf("unsupported address type %d: only IPv4 is supported", ints[0])
+	}
+
+	return net.IPv4(byte(ints[1]), byte(ints[2]), byte(ints[3]), byte(ints[4])), nil
+}
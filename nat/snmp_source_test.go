@@ -0,0 +1,48 @@
+package nat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseCnatBindIndex(t *testing.T) {
+	// proto=3(tcp), localAddrType=1(ipv4), local=10.0.0.5:1234,
+	// globalAddrType=1(ipv4), global=203.0.113.9:5678
+	suffix := "3.1.10.0.0.5.1234.1.203.0.113.9.5678"
+
+	proto, nat, err := parseCnatBindIndex(suffix)
+	if err != nil {
+		t.Fatalf("parseCnatBindIndex: %v", err)
+	}
+
+	if proto != cnatProtoTCP {
+		t.Errorf("proto = %d, want %d", proto, cnatProtoTCP)
+	}
+	if !nat.InsideLocal.Equal(net.ParseIP("10.0.0.5")) || nat.InsideLocalPort != 1234 {
+		t.Errorf("inside local = %s:%d, want 10.0.0.5:1234", nat.InsideLocal, nat.InsideLocalPort)
+	}
+	if !nat.OutsideGlobal.Equal(net.ParseIP("203.0.113.9")) || nat.OutsideGlobalPort != 5678 {
+		t.Errorf("outside global = %s:%d, want 203.0.113.9:5678", nat.OutsideGlobal, nat.OutsideGlobalPort)
+	}
+}
+
+func TestParseCnatBindIndexRejectsNonIPv4(t *testing.T) {
+	// globalAddrType=2 (not ipv4)
+	suffix := "3.1.10.0.0.5.1234.2.203.0.113.9.5678"
+
+	if _, _, err := parseCnatBindIndex(suffix); err == nil {
+		t.Fatal("expected an error for a non-IPv4 address type")
+	}
+}
+
+func TestParseCnatAddrBindIndex(t *testing.T) {
+	suffix := "1.10.0.0.5"
+
+	ip, err := parseCnatAddrBindIndex(suffix)
+	if err != nil {
+		t.Fatalf("parseCnatAddrBindIndex: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("ip = %s, want 10.0.0.5", ip)
+	}
+}
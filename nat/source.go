@@ -0,0 +1,43 @@
+package nat
+
+import (
+	"bufio"
+	"io"
+)
+
+// Source produces a stream of NAT translation records, regardless of where
+// they come from (a text dump, an SSH session, SNMP, ...).
+type Source interface {
+	// Next returns the next NAT record. It returns io.EOF once the
+	// underlying stream is exhausted.
+	Next() (*NAT, error)
+}
+
+// TextSource parses NAT records from the text output of
+// `show ip nat translations` (terse, `verbose` or `extended`), such as a
+// file or a captured CLI session.
+type TextSource struct {
+	scanner *bufio.Scanner
+}
+
+// NewTextSource wraps r, scanning it for NAT translation records.
+func NewTextSource(r io.Reader) *TextSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(routeSplitFunc)
+	return &TextSource{scanner: scanner}
+}
+
+func (s *TextSource) Next() (*NAT, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	nat := new(NAT)
+	if err := nat.Parse(s.scanner.Bytes()); err != nil {
+		return nil, err
+	}
+	return nat, nil
+}
@@ -0,0 +1,118 @@
+package nat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ShowNATCommand is the IOS command SSHSource runs to dump NAT translations.
+const ShowNATCommand = "show ip nat translations verbose"
+
+// SSHSource dials an IOS device over SSH, runs ShowNATCommand, and streams
+// the parsed NAT records from its output as they arrive.
+type SSHSource struct {
+	client  *ssh.Client
+	session *ssh.Session
+	scanner *bufio.Scanner
+	stderr  *bytes.Buffer
+	waited  bool
+}
+
+// NewSSHSource dials addr (host:port), authenticates as user/password, and
+// starts streaming NAT translations from the device. The device's host key
+// is verified against knownHostsPath (in the usual ssh_known_hosts format);
+// a device whose key isn't there, or whose key has changed, is rejected
+// rather than trusted blindly.
+func NewSSHSource(addr, user, password, knownHostsPath string) (*SSHSource, error) {
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading known_hosts from %s: %w", knownHostsPath, err)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s: %w", addr, err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("opening session: %w", err)
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		client.Close()
+		return nil, fmt.Errorf("attaching to stdout: %w", err)
+	}
+
+	stderr := new(bytes.Buffer)
+	session.Stderr = stderr
+
+	if err := session.Start(ShowNATCommand); err != nil {
+		session.Close()
+		client.Close()
+		return nil, fmt.Errorf("running %q: %w", ShowNATCommand, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Split(routeSplitFunc)
+
+	return &SSHSource{client: client, session: session, scanner: scanner, stderr: stderr}, nil
+}
+
+func (s *SSHSource) Next() (*NAT, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		if err := s.waitForExit(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	nat := new(NAT)
+	if err := nat.Parse(s.scanner.Bytes()); err != nil {
+		return nil, err
+	}
+	return nat, nil
+}
+
+// waitForExit waits for ShowNATCommand to finish and turns a non-zero exit
+// (bad privilege level, an unsupported command, ...) into an error instead
+// of letting it look identical to "zero translations". It's safe to call
+// more than once; only the first call actually waits.
+func (s *SSHSource) waitForExit() error {
+	if s.waited {
+		return nil
+	}
+	s.waited = true
+
+	if err := s.session.Wait(); err != nil {
+		if stderr := strings.TrimSpace(s.stderr.String()); stderr != "" {
+			return fmt.Errorf("%q failed: %w: %s", ShowNATCommand, err, stderr)
+		}
+		return fmt.Errorf("%q failed: %w", ShowNATCommand, err)
+	}
+	return nil
+}
+
+// Close releases the underlying SSH session and connection.
+func (s *SSHSource) Close() error {
+	s.session.Close()
+	return s.client.Close()
+}
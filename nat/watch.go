@@ -0,0 +1,160 @@
+package nat
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// EventType identifies how a NAT translation's lifecycle changed between
+// two polls of a Watcher.
+type EventType string
+
+const (
+	EventCreated   EventType = "translation_created"
+	EventRemoved   EventType = "translation_removed"
+	EventRefreshed EventType = "translation_refreshed"
+)
+
+// Event describes a single lifecycle change observed by a Watcher.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	NAT  *NAT      `json:"nat"`
+}
+
+// Key identifies a NAT translation across polls, independent of the
+// timestamps and timeout that naturally change between samples.
+type Key struct {
+	Proto             NATProto
+	InsideLocal       string
+	InsideLocalPort   int
+	OutsideGlobal     string
+	OutsideGlobalPort int
+}
+
+func keyOf(nat *NAT) Key {
+	return Key{
+		Proto:             nat.Proto,
+		InsideLocal:       nat.InsideLocal.String(),
+		InsideLocalPort:   nat.InsideLocalPort,
+		OutsideGlobal:     nat.OutsideGlobal.String(),
+		OutsideGlobalPort: nat.OutsideGlobalPort,
+	}
+}
+
+type watchEntry struct {
+	nat       *NAT
+	removedAt time.Time // zero while still present
+}
+
+// Watcher repeatedly opens a new Source via NewSource, diffs the resulting
+// translations against the previous poll, and reports lifecycle Events.
+type Watcher struct {
+	// NewSource opens a fresh Source for each poll. It's a factory rather
+	// than a single long-lived Source because most Sources (SSH, SNMP)
+	// represent one round-trip, not an ongoing stream.
+	NewSource func() (Source, error)
+
+	// Interval is the time between the start of one poll and the start of
+	// the next.
+	Interval time.Duration
+
+	// MaxAge is how long a removed translation is kept in memory (so it
+	// isn't reported as removed more than once) before being forgotten.
+	MaxAge time.Duration
+
+	snapshot map[Key]*watchEntry
+}
+
+// Run polls until ctx is done or a poll returns an error, calling emit for
+// every lifecycle Event observed along the way.
+//
+// Each iteration measures how long the poll took and sleeps only the
+// remainder of Interval, so a slow poll doesn't cause every subsequent poll
+// to drift later and later. Because the loop is strictly sequential, a
+// poll that runs long simply delays the next one rather than piling up
+// concurrent polls.
+func (w *Watcher) Run(ctx context.Context, emit func(Event) error) error {
+	if w.snapshot == nil {
+		w.snapshot = make(map[Key]*watchEntry)
+	}
+
+	for {
+		start := time.Now()
+
+		if err := w.poll(emit); err != nil {
+			return err
+		}
+
+		elapsed := time.Since(start)
+		sleep := w.Interval - elapsed
+		if sleep < 0 {
+			sleep = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+	}
+}
+
+func (w *Watcher) poll(emit func(Event) error) error {
+	src, err := w.NewSource()
+	if err != nil {
+		return err
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	now := time.Now()
+	seen := make(map[Key]bool)
+
+	for {
+		n, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key := keyOf(n)
+		seen[key] = true
+
+		prev, existed := w.snapshot[key]
+		switch {
+		case !existed || !prev.removedAt.IsZero():
+			if err := emit(Event{Type: EventCreated, Time: now, NAT: n}); err != nil {
+				return err
+			}
+		case prev.nat.Used != n.Used || prev.nat.Timeout != n.Timeout:
+			if err := emit(Event{Type: EventRefreshed, Time: now, NAT: n}); err != nil {
+				return err
+			}
+		}
+
+		w.snapshot[key] = &watchEntry{nat: n}
+	}
+
+	for key, entry := range w.snapshot {
+		if seen[key] {
+			continue
+		}
+		if entry.removedAt.IsZero() {
+			entry.removedAt = now
+			if err := emit(Event{Type: EventRemoved, Time: now, NAT: entry.nat}); err != nil {
+				return err
+			}
+			continue
+		}
+		if w.MaxAge > 0 && now.Sub(entry.removedAt) > w.MaxAge {
+			delete(w.snapshot, key)
+		}
+	}
+
+	return nil
+}
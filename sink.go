@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ThisGuyCodes/cisco/nat"
+)
+
+// eventSink receives lifecycle events from a Watcher as newline-delimited
+// JSON, however it ultimately delivers them.
+type eventSink interface {
+	Write(nat.Event) error
+	Close() error
+}
+
+// openSink builds the eventSink described by spec:
+//   - "-"                     stdout
+//   - "unix:/path/to.sock"    a Unix domain socket
+//   - "http://host/path"      an HTTP webhook, one POST per event
+//   - anything else           a file path, opened for append
+func openSink(spec string) (eventSink, error) {
+	switch {
+	case spec == "-":
+		return &writerSink{w: os.Stdout}, nil
+	case strings.HasPrefix(spec, "unix:"):
+		conn, err := net.Dial("unix", strings.TrimPrefix(spec, "unix:"))
+		if err != nil {
+			return nil, err
+		}
+		return &writerSink{w: conn, closer: conn}, nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return &httpSink{url: spec, client: http.DefaultClient}, nil
+	default:
+		f, err := os.OpenFile(spec, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &writerSink{w: f, closer: f}, nil
+	}
+}
+
+// writerSink writes each event as a line of JSON to an io.Writer, optionally
+// closing an underlying resource (a file or socket) when done.
+type writerSink struct {
+	w      interface{ Write([]byte) (int, error) }
+	closer interface{ Close() error }
+}
+
+func (s *writerSink) Write(e nat.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// httpSink POSTs each event as a JSON body to url.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) Write(e nat.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error { return nil }